@@ -5,12 +5,15 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"github.com/cespare/xxhash/v2"
 	"github.com/slatedb/slatedb-go/internal/assert"
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/slatedb/slatedb-go/slatedb/common"
 	"hash/crc32"
+	"io"
 	"math"
+	"sync"
 )
 
 var (
@@ -20,12 +23,71 @@ var (
 const (
 	// TODO(thrawn01): Remove once KeyValue refactor is complete
 	Tombstone = math.MaxUint32
+
+	// defaultRestartInterval is the number of entries between "restart points" in a
+	// block. A restart point stores its key in full (KeyPrefixLen == 0) rather than
+	// prefix-compressed, so a reader can jump to it without decoding anything before
+	// it. This is the same layout LevelDB/goleveldb use for their data blocks.
+	defaultRestartInterval = 16
+
+	// blockMagicV1 is written as the final 2 bytes of the uncompressed buffer for
+	// blocks encoded with restart points (see Encode/Decode). It is chosen so that it
+	// can't be mistaken for a v0 Block.Offsets count, which would require a single
+	// block to hold 65535 entries.
+	blockMagicV1 = math.MaxUint16
+
+	// blockMagicV2 marks a v1 block (restart points) that additionally carries a
+	// Block.Filter section. blockMagicV1 above is unambiguous for a v0 Offsets
+	// count, so this is simply the next value down.
+	blockMagicV2 = math.MaxUint16 - 1
 )
 
 type Block struct {
 	FirstKey []byte
 	Data     []byte
 	Offsets  []uint16
+	// Restarts holds the absolute offsets into Data of each restart point, in
+	// ascending order. Restarts is nil for blocks decoded from the legacy v0 format.
+	Restarts []uint16
+	// Filter holds a serialized FilterPolicy bitmap covering every key in the
+	// block, or nil if the block was built without WithFilterPolicy. See MayContain.
+	Filter []byte
+	// RowCodec identifies which row encoding scheme Data was written with. It
+	// defaults to RowCodecV0, the original fixed-width encoding.
+	RowCodec RowCodecID
+
+	// pooled is the raw read buffer DecodeFrom drew from decodeBufPool, or nil
+	// for blocks produced by Decode/DecodeAuto/Builder.Build. See Release.
+	pooled *[]byte
+}
+
+// RowCodecID identifies which row-encoding scheme produced Block.Data, so a
+// reader can select the matching decoder without being told out of band.
+type RowCodecID uint8
+
+const (
+	// RowCodecV0 is the original row encoding (see v0RowCodec in row.go):
+	// fixed 2-byte KeyPrefixLen/KeySuffixLen fields and a 4-byte ValueLen,
+	// with math.MaxUint32 (Tombstone) as the deletion sentinel.
+	RowCodecV0 RowCodecID = 0
+
+	// RowCodecV1 replaces those fixed-width fields with varints and a
+	// single-byte tombstone tag (see v1RowCodec in row.go), trimming several
+	// bytes per row on workloads with short keys and values. Select it with
+	// WithRowCodec.
+	RowCodecV1 RowCodecID = 1
+)
+
+// Release returns the pooled read buffer backing a Block decoded via
+// DecodeFrom to decodeBufPool, and clears b so a use-after-release reads zero
+// values instead of freed memory. Release is a no-op for blocks that don't
+// own a pooled buffer (anything not produced by DecodeFrom).
+func (b *Block) Release() {
+	if b.pooled == nil {
+		return
+	}
+	decodeBufPool.Put(b.pooled)
+	*b = Block{}
 }
 
 // Encode encodes the Block into a byte slice using the following format
@@ -41,6 +103,16 @@ type Block struct {
 // |  |  ...                                    |  |
 // |  +-----------------------------------------+  |
 // |  +-----------------------------------------+  |
+// |  |  Block.Restarts                         |  |
+// |  |  +-----------------------------------+  |  |
+// |  |  |  Offset of restart point (2 bytes)|  |  |
+// |  |  +-----------------------------------+  |  |
+// |  |  ...                                    |  |
+// |  +-----------------------------------------+  |
+// |  +-----------------------------------------+  |
+// |  |  Number of Restarts (2 bytes)           |  |
+// |  +-----------------------------------------+  |
+// |  +-----------------------------------------+  |
 // |  |  Block.Offsets                          |  |
 // |  |  +-----------------------------------+  |  |
 // |  |  |  Offset of KeyValue (2 bytes)     |  |  |
@@ -50,121 +122,584 @@ type Block struct {
 // |  +-----------------------------------------+  |
 // |  |  Number of Offsets (2 bytes)            |  |
 // |  +-----------------------------------------+  |
-// |  |  Checksum (4 bytes)                     |  |
 // |  +-----------------------------------------+  |
+// |  |  Block.Filter (optional)                |  |
+// |  |  +-----------------------------------+  |  |
+// |  |  |  Filter bitmap (FilterLen bytes)  |  |  |
+// |  |  +-----------------------------------+  |  |
+// |  |  |  FilterLen (4 bytes)              |  |  |
+// |  |  +-----------------------------------+  |  |
+// |  +-----------------------------------------+  |
+// |  +-----------------------------------------+  |
+// |  |  blockMagicV1 / blockMagicV2 (2 bytes)  |  |
+// |  +-----------------------------------------+  |
+// +-----------------------------------------------+
+// |  Compression codec (1 byte)                   |
+// |  RowCodec (1 byte)                             |
+// |  Checksum (4 bytes)                            |
 // +-----------------------------------------------+
+//
+// Encode always writes the Restarts section and a trailing magic, even when
+// Block.Restarts is empty. Blocks in the older v0 layout, which has neither,
+// are still read back by Decode. The Filter section is itself optional within the v1
+// layout: it's only written when Block.Filter is non-nil, signaled by
+// blockMagicV2 instead of blockMagicV1 (see Decode).
+//
+// The codec used to compress Block.Data is itself persisted as a single byte
+// between the compressed payload and the checksum (outside the compressed
+// region, so it can be read before anything is decompressed). DecodeAuto uses
+// it to recover codec without the caller having to already know it; Decode
+// still takes codec explicitly for callers that already track it themselves,
+// e.g. because they chose it from SSTable-level configuration rather than
+// per block. This package only persists whichever compress.Codec value the
+// caller passes; it doesn't add codecs or pick one per SSTable -- that's
+// compress's and the SST writer's job respectively, neither of which this
+// package owns.
+//
+// TODO(chunk0-3): Snappy/Zstd codec support, WithZstdLevel, and per-SSTable
+// codec configuration are still unimplemented -- they need compress package
+// changes this checkout doesn't have. Don't treat that backlog item as
+// closed by this trailer-byte persistence alone.
+//
+// Block.RowCodec is persisted the same way, right next to the compression
+// codec byte, so a reader can tell which row encoding produced Block.Data.
 func Encode(b *Block, codec compress.Codec) ([]byte, error) {
-	bufSize := len(b.Data) + len(b.Offsets)*common.SizeOfUint16 + common.SizeOfUint16
+	bufSize := len(b.Data) +
+		len(b.Restarts)*common.SizeOfUint16 + common.SizeOfUint16 +
+		len(b.Offsets)*common.SizeOfUint16 + common.SizeOfUint16 +
+		len(b.Filter) + common.SizeOfUint32 +
+		common.SizeOfUint16
 
 	buf := make([]byte, 0, bufSize)
 	buf = append(buf, b.Data...)
 
+	for _, restart := range b.Restarts {
+		buf = binary.BigEndian.AppendUint16(buf, restart)
+	}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(b.Restarts)))
+
 	for _, offset := range b.Offsets {
 		buf = binary.BigEndian.AppendUint16(buf, offset)
 	}
 	buf = binary.BigEndian.AppendUint16(buf, uint16(len(b.Offsets)))
 
+	if b.Filter != nil {
+		buf = append(buf, b.Filter...)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(b.Filter)))
+		buf = binary.BigEndian.AppendUint16(buf, blockMagicV2)
+	} else {
+		buf = binary.BigEndian.AppendUint16(buf, blockMagicV1)
+	}
+
 	compressed, err := compress.Encode(buf, codec)
 	if err != nil {
 		return nil, err
 	}
 
-	// Make a new buffer exactly the size of the compressed plus the checksum
-	buf = make([]byte, 0, len(compressed)+common.SizeOfUint32)
-	buf = append(buf, compressed...)
-	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(compressed))
-	return buf, nil
+	// payload is the checksummed region: the compressed block plus the codec
+	// and row-codec bytes DecodeAuto recovers them from.
+	payload := make([]byte, 0, len(compressed)+2)
+	payload = append(payload, compressed...)
+	payload = append(payload, byte(codec))
+	payload = append(payload, byte(b.RowCodec))
+
+	out := make([]byte, 0, len(payload)+common.SizeOfUint32)
+	out = append(out, payload...)
+	out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(payload))
+	return out, nil
 }
 
-// Decode converts the encoded byte slice into the provided Block
+// Decode converts the encoded byte slice into the provided Block, using codec
+// to decompress Block.Data. Prefer DecodeAuto when the caller doesn't already
+// know which codec was used to encode input.
 // TODO(thrawn01): The asserts here should be returned as errors as its impossible
 //  to know which block is corrupt without context, nor is it possible to gracefully skip
 //  corrupt blocks if these assertions fail.
 func Decode(b *Block, input []byte, codec compress.Codec) error {
-	assert.True(len(input) > 6, "corrupt block; block is too small; must be at least 6 bytes")
+	compressed, _, rowCodec, err := splitEncodedTrailer(input)
+	if err != nil {
+		return err
+	}
+	return decodeBlock(b, compressed, codec, rowCodec)
+}
 
-	// last 4 bytes hold the checksum
-	checksumIndex := len(input) - common.SizeOfUint32
-	compressed := input[:checksumIndex]
-	if binary.BigEndian.Uint32(input[checksumIndex:]) != crc32.ChecksumIEEE(compressed) {
+// DecodeAuto converts the encoded byte slice into the provided Block,
+// recovering the compress.Codec Encode persisted alongside the checksum
+// instead of requiring the caller to already know it.
+func DecodeAuto(b *Block, input []byte) error {
+	compressed, codec, rowCodec, err := splitEncodedTrailer(input)
+	if err != nil {
+		return err
+	}
+	return decodeBlock(b, compressed, codec, rowCodec)
+}
+
+// decodeBufPool holds raw read buffers for DecodeFrom, amortizing allocation
+// across the many small block reads an SST reader does against object storage.
+var decodeBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0)
+		return &buf
+	},
+}
+
+// DecodeFrom decodes a Block directly from an io.ReaderAt spanning
+// [off, off+length) of backing storage, for callers (the SST reader) that
+// would otherwise read the whole block into a []byte before calling Decode
+// just to discard that buffer again. For large blocks fetched from object
+// storage this avoids holding both the raw read and the decoded Block in
+// memory at once.
+//
+// It reads the 4-byte CRC trailer with one small read before reading the
+// rest of the block, and checks it against that payload once the payload has
+// been read. The read buffer comes from decodeBufPool instead of a
+// fresh allocation per call, and for an uncompressed block Block.Data ends up
+// referencing that same buffer rather than a copy of it. For a compressed
+// block, compress.Decode still allocates its own output buffer -- there's no
+// API for it to decompress into memory DecodeFrom already owns -- so
+// Block.Data there is backed by that allocation instead of a pooled one;
+// Release reclaims the read buffer for reuse on the next call regardless, it
+// just isn't the one backing Block.Data in the compressed case. Call
+// Block.Release() once dst is no longer needed.
+//
+// TODO(chunk0-5): the pooled, zero-copy decode this was meant to provide for
+// object-storage-sized blocks only actually applies to the CodecNone path --
+// a compressed block still costs the same extra allocation plain Decode
+// does, since compress.Decode has no decompress-into-buffer API to pool
+// against. Don't treat that backlog item as fully closed until it does.
+func DecodeFrom(r io.ReaderAt, off, length int64, codec compress.Codec, dst *Block) error {
+	assert.True(length > int64(common.SizeOfUint32)+2,
+		"corrupt block; block is too small; must be at least %d bytes", common.SizeOfUint32+2)
+
+	var checksumBuf [common.SizeOfUint32]byte
+	if _, err := r.ReadAt(checksumBuf[:], off+length-int64(common.SizeOfUint32)); err != nil {
+		return fmt.Errorf("reading block checksum: %w", err)
+	}
+	wantChecksum := binary.BigEndian.Uint32(checksumBuf[:])
+
+	pooled := decodeBufPool.Get().(*[]byte)
+	payloadLen := int(length) - common.SizeOfUint32
+	if cap(*pooled) < payloadLen {
+		*pooled = make([]byte, payloadLen)
+	} else {
+		*pooled = (*pooled)[:payloadLen]
+	}
+	payload := *pooled
+
+	if _, err := r.ReadAt(payload, off); err != nil {
+		decodeBufPool.Put(pooled)
+		return fmt.Errorf("reading block payload: %w", err)
+	}
+
+	if crc32.ChecksumIEEE(payload) != wantChecksum {
+		decodeBufPool.Put(pooled)
 		return common.ErrChecksumMismatch
 	}
 
+	// Strip the codec and row-codec bytes Encode persists alongside the
+	// checksum (see splitEncodedTrailer); DecodeFrom takes codec explicitly
+	// rather than trusting it, the same way Decode does.
+	rowCodec := RowCodecID(payload[len(payload)-1])
+	compressed := payload[:len(payload)-2]
+
+	if err := decodeBlock(dst, compressed, codec, rowCodec); err != nil {
+		decodeBufPool.Put(pooled)
+		return err
+	}
+
+	dst.pooled = pooled
+	return nil
+}
+
+// splitEncodedTrailer verifies the checksum Encode appended and splits off the
+// still-compressed block payload, the compression codec, and the row codec
+// stored next to it.
+func splitEncodedTrailer(input []byte) ([]byte, compress.Codec, RowCodecID, error) {
+	assert.True(len(input) > common.SizeOfUint32+2,
+		"corrupt block; block is too small; must be at least %d bytes", common.SizeOfUint32+2)
+
+	checksumIndex := len(input) - common.SizeOfUint32
+	payload := input[:checksumIndex]
+	if binary.BigEndian.Uint32(input[checksumIndex:]) != crc32.ChecksumIEEE(payload) {
+		return nil, 0, 0, common.ErrChecksumMismatch
+	}
+
+	rowCodecIndex := len(payload) - 1
+	codecIndex := rowCodecIndex - 1
+	return payload[:codecIndex], compress.Codec(payload[codecIndex]), RowCodecID(payload[rowCodecIndex]), nil
+}
+
+func decodeBlock(b *Block, compressed []byte, codec compress.Codec, rowCodec RowCodecID) error {
 	buf, err := compress.Decode(compressed, codec)
 	if err != nil {
 		return err
 	}
+	return parseBlock(b, buf, rowCodec)
+}
 
+// parseBlock fills b from buf, the fully decompressed block payload Encode
+// produced (everything up to but not including the codec/row-codec/checksum
+// trailer). decodeBlock calls this straight after compress.Decode; Decode,
+// DecodeAuto and DecodeFrom all reach it through decodeBlock.
+func parseBlock(b *Block, buf []byte, rowCodec RowCodecID) error {
 	assert.True(len(buf) > common.SizeOfUint16,
 		"corrupt block; uncompressed block is too small; must be at least %d bytes", common.SizeOfUint16)
 
-	// The last 2 bytes hold the offset count
-	offsetCountIndex := len(buf) - common.SizeOfUint16
-	offsetCount := binary.BigEndian.Uint16(buf[offsetCountIndex:])
+	// The last 2 bytes are either a v0 offset count, or a magic value marking a
+	// block that also carries a Restarts section (blockMagicV1) and, optionally,
+	// a Filter section too (blockMagicV2).
+	trailerEnd := len(buf) - common.SizeOfUint16
+	tail := binary.BigEndian.Uint16(buf[trailerEnd:])
+
+	var offsets, restarts []uint16
+	var filter []byte
+	var dataEnd int
+
+	if tail == blockMagicV1 || tail == blockMagicV2 {
+		offsetCountIndex := trailerEnd
+
+		if tail == blockMagicV2 {
+			assert.True(offsetCountIndex > common.SizeOfUint32,
+				"corrupt block; v2 block trailer is truncated")
+			filterLenIndex := offsetCountIndex - common.SizeOfUint32
+			filterLen := binary.BigEndian.Uint32(buf[filterLenIndex:])
+
+			filterStartIndex := filterLenIndex - int(filterLen)
+			assert.True(filterStartIndex >= 0,
+				"corrupt block; filter index %d cannot be negative", filterStartIndex)
+			filter = buf[filterStartIndex:filterLenIndex]
+			offsetCountIndex = filterStartIndex
+		}
 
-	offsetStartIndex := offsetCountIndex - (int(offsetCount) * common.SizeOfUint16)
-	assert.True(offsetStartIndex >= 0,
-		"corrupt block; offset index %d cannot be negative", offsetStartIndex)
-	offsets := make([]uint16, 0, offsetCount)
+		assert.True(offsetCountIndex > common.SizeOfUint16,
+			"corrupt block; v1 block trailer is truncated")
+		offsetCountIndex -= common.SizeOfUint16
+		offsetCount := binary.BigEndian.Uint16(buf[offsetCountIndex:])
+
+		offsetStartIndex := offsetCountIndex - (int(offsetCount) * common.SizeOfUint16)
+		assert.True(offsetStartIndex >= 0,
+			"corrupt block; offset index %d cannot be negative", offsetStartIndex)
+		offsets = make([]uint16, 0, offsetCount)
+		for i := 0; i < int(offsetCount); i++ {
+			index := offsetStartIndex + (i * common.SizeOfUint16)
+			assert.True(index >= 0 && index <= len(buf), "corrupt block; block offset[%d] is invalid", index)
+			offsets = append(offsets, binary.BigEndian.Uint16(buf[index:]))
+		}
+
+		restartCountIndex := offsetStartIndex - common.SizeOfUint16
+		assert.True(restartCountIndex >= 0,
+			"corrupt block; restart count index %d cannot be negative", restartCountIndex)
+		restartCount := binary.BigEndian.Uint16(buf[restartCountIndex:])
+
+		restartStartIndex := restartCountIndex - (int(restartCount) * common.SizeOfUint16)
+		assert.True(restartStartIndex >= 0,
+			"corrupt block; restart index %d cannot be negative", restartStartIndex)
+		restarts = make([]uint16, 0, restartCount)
+		for i := 0; i < int(restartCount); i++ {
+			index := restartStartIndex + (i * common.SizeOfUint16)
+			assert.True(index >= 0 && index <= len(buf), "corrupt block; block restart[%d] is invalid", index)
+			restarts = append(restarts, binary.BigEndian.Uint16(buf[index:]))
+		}
+
+		dataEnd = restartStartIndex
+	} else {
+		offsetCount := tail
+		offsetStartIndex := trailerEnd - (int(offsetCount) * common.SizeOfUint16)
+		assert.True(offsetStartIndex >= 0,
+			"corrupt block; offset index %d cannot be negative", offsetStartIndex)
+		offsets = make([]uint16, 0, offsetCount)
+		for i := 0; i < int(offsetCount); i++ {
+			index := offsetStartIndex + (i * common.SizeOfUint16)
+			assert.True(index >= 0 && index <= len(buf), "corrupt block; block offset[%d] is invalid", index)
+			offsets = append(offsets, binary.BigEndian.Uint16(buf[index:]))
+		}
 
-	for i := 0; i < int(offsetCount); i++ {
-		index := offsetStartIndex + (i * common.SizeOfUint16)
-		assert.True(index >= 0 && index <= len(buf), "corrupt block; block offset[%d] is invalid", index)
-		offsets = append(offsets, binary.BigEndian.Uint16(buf[index:]))
+		dataEnd = offsetStartIndex
 	}
 
-	b.Data = buf[:offsetStartIndex]
+	b.Data = buf[:dataEnd]
 	b.Offsets = offsets
+	b.Restarts = restarts
+	b.Filter = filter
+	b.RowCodec = rowCodec
 
 	assert.True(len(b.Offsets) != 0, "corrupt block; block Block.Offsets must be greater than 0")
 
-	// Extract the first key in the block
-	keyLen := binary.BigEndian.Uint16(b.Data[b.Offsets[0]:])
-	b.FirstKey = b.Data[b.Offsets[0]+2 : b.Offsets[0]+2+keyLen]
+	// The row at Offsets[0] is always a restart point (KeyPrefixLen == 0), so
+	// its KeySuffix is the full first key regardless of which RowCodec wrote
+	// it. Decode it through rowCodecFor instead of assuming a byte layout,
+	// since that layout differs between RowCodecV0 and RowCodecV1.
+	first, _ := rowCodecFor(rowCodec).Decode(b.Data[b.Offsets[0]:])
+	b.FirstKey = first.KeySuffix
 
 	return nil
 }
 
+// restartFloor returns the index into restarts of the largest restart point whose
+// full key is <= target, or -1 if target is smaller than every restart key.
+//
+// NewIteratorAtKey (in iterator.go) uses this to binary-search a block in
+// O(log n) instead of scanning every entry: it seeks to restarts[restartFloor(...)]
+// and linearly re-materializes prefix-compressed rows forward from there until it
+// reaches the first key >= target.
+func restartFloor(data []byte, restarts []uint16, rowCodec RowCodecID, target []byte) int {
+	lo, hi := 0, len(restarts)-1
+	result := -1
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+		key := restartKey(data, restarts[mid], rowCodec)
+		if bytes.Compare(key, target) <= 0 {
+			result = mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return result
+}
+
+// restartKey reads the full, uncompressed key stored at a restart point. A
+// restart point's row always has KeyPrefixLen == 0 (see Builder.Add), so its
+// KeySuffix is the full key, but the byte layout of that row still differs
+// between RowCodecV0 and RowCodecV1 -- decode through rowCodecFor instead of
+// assuming v0RowCodec's fixed-width KeySuffixLen field, the same way
+// NextEntry/indexGE/decodeAt/parseBlock's FirstKey extraction do.
+func restartKey(data []byte, offset uint16, rowCodec RowCodecID) []byte {
+	row, _ := rowCodecFor(rowCodec).Decode(data[offset:])
+	return row.KeySuffix
+}
+
+// restartWindowEnd returns the end of the byte range in data spanned by the
+// restart window beginning at restarts[index]: the offset of the next restart
+// point, or len(data) if index is the block's last restart point.
+//
+// decodeAt (in iterator.go), which PrevEntry and SeekLE (in reverse.go) call,
+// uses this to bound its replay: it starts at the restart point at or before
+// the target row and decodes the prefix-compressed rows across
+// [restarts[index], restartWindowEnd(...)) forward until it reaches that row.
+// No decoded keys are kept between calls, so every PrevEntry replays its
+// row's restart window from the start again.
+func restartWindowEnd(data []byte, restarts []uint16, index int) uint16 {
+	if index+1 < len(restarts) {
+		return restarts[index+1]
+	}
+	return uint16(len(data))
+}
+
+// FilterPolicy builds and queries a compact summary of a block's keys, letting
+// a reader rule out a missing key without paging in Block.Data or running
+// NewIteratorAtKey. A false return from MayContain is a guarantee the key is
+// absent; a true return may be a false positive.
+type FilterPolicy interface {
+	// Build serializes a filter bitmap covering keys.
+	Build(keys [][]byte) []byte
+	// MayContain reports whether key might be present in filter, a bitmap
+	// previously returned by Build.
+	MayContain(filter []byte, key []byte) bool
+}
+
+// defaultBitsPerKey is the bits-per-key used by NewBloomFilterPolicy when the
+// caller doesn't otherwise specify one. 10 bits/key gives ~1% false positives,
+// LevelDB's default tradeoff.
+const defaultBitsPerKey = 10
+
+// defaultFilterPolicy interprets Block.Filter bitmaps produced by the bloom
+// policy at its default bits-per-key. A block's filter is self-describing
+// enough (see bloomFilterPolicy.Build) that any bloomFilterPolicy instance can
+// query a filter built by another, so Block.MayContain doesn't need to persist
+// which bits-per-key produced it.
+var defaultFilterPolicy FilterPolicy = NewBloomFilterPolicy(defaultBitsPerKey)
+
+// bloomFilterPolicy is a standard Bloom filter using double hashing over
+// xxhash to derive the k probe positions from a single hash, the same trick
+// used by LevelDB's FilterPolicy.
+type bloomFilterPolicy struct {
+	bitsPerKey int
+}
+
+// NewBloomFilterPolicy returns a FilterPolicy backed by a Bloom filter sized
+// for bitsPerKey bits per key. 10 is a reasonable default (~1% false positive
+// rate); higher values trade block size for fewer false positives.
+func NewBloomFilterPolicy(bitsPerKey int) FilterPolicy {
+	return &bloomFilterPolicy{bitsPerKey: bitsPerKey}
+}
+
+// bloomNumHashes mirrors LevelDB's choice of k = ln(2) * bitsPerKey, clamped
+// to a sane range.
+func bloomNumHashes(bitsPerKey int) uint8 {
+	k := int(float64(bitsPerKey) * 0.69)
+	if k < 1 {
+		k = 1
+	}
+	if k > 30 {
+		k = 30
+	}
+	return uint8(k)
+}
+
+func (p *bloomFilterPolicy) Build(keys [][]byte) []byte {
+	numBits := len(keys) * p.bitsPerKey
+	if numBits < 64 {
+		numBits = 64
+	}
+	numBytes := (numBits + 7) / 8
+	numBits = numBytes * 8
+	numHashes := bloomNumHashes(p.bitsPerKey)
+
+	// The trailing byte stores numHashes so MayContain can query the filter
+	// without being told which policy produced it.
+	filter := make([]byte, numBytes+1)
+	for _, key := range keys {
+		h := xxhash.Sum64(key)
+		// Rotate rather than re-hash for each of the k probes, per Kirsch-Mitzenmacher.
+		delta := (h >> 17) | (h << 47)
+		for i := uint8(0); i < numHashes; i++ {
+			bitPos := h % uint64(numBits)
+			filter[bitPos/8] |= 1 << (bitPos % 8)
+			h += delta
+		}
+	}
+	filter[numBytes] = byte(numHashes)
+	return filter
+}
+
+func (p *bloomFilterPolicy) MayContain(filter []byte, key []byte) bool {
+	if len(filter) < 2 {
+		return len(filter) == 0
+	}
+
+	numBytes := len(filter) - 1
+	numHashes := filter[numBytes]
+	if numHashes > 30 {
+		// Reserved for a future encoding this policy doesn't understand; don't
+		// filter out what might be a real match.
+		return true
+	}
+
+	h := xxhash.Sum64(key)
+	delta := (h >> 17) | (h << 47)
+	numBits := uint64(numBytes * 8)
+	for i := uint8(0); i < numHashes; i++ {
+		bitPos := h % numBits
+		if filter[bitPos/8]&(1<<(bitPos%8)) == 0 {
+			return false
+		}
+		h += delta
+	}
+	return true
+}
+
+// MayContain reports whether key might be present in the block. A false
+// return is a guarantee key is absent, letting SSTable readers skip
+// NewIteratorAtKey entirely for a point lookup that misses. Blocks without a
+// filter (built without WithFilterPolicy, or decoded from the v0/v1 formats)
+// always report true, deferring to the iterator.
+func (b *Block) MayContain(key []byte) bool {
+	if len(b.Filter) == 0 {
+		return true
+	}
+	return defaultFilterPolicy.MayContain(b.Filter, key)
+}
+
 type Builder struct {
-	offsets   []uint16
-	data      []byte
-	blockSize uint64
-	firstKey  []byte
+	offsets      []uint16
+	restarts     []uint16
+	data         []byte
+	blockSize    uint64
+	firstKey     []byte
+	restartBase  []byte
+	keys         [][]byte
+	filterPolicy FilterPolicy
+	rowCodec     RowCodecID
+}
+
+// BuilderOption configures optional behavior on a Builder created by NewBuilder.
+type BuilderOption func(*Builder)
+
+// WithFilterPolicy has the Builder compute a FilterPolicy bitmap over every key
+// added and attach it to the built Block as Block.Filter, so SSTable readers
+// can rule out missing keys without running NewIteratorAtKey. See
+// NewBloomFilterPolicy for the default implementation.
+func WithFilterPolicy(policy FilterPolicy) BuilderOption {
+	return func(b *Builder) {
+		b.filterPolicy = policy
+	}
 }
 
-// NewBuilder builds a block of key values in the v0RowCodec
-// format along with the Block.Offsets which point to the
-// beginning of each key/value.
+// WithRowCodec selects which RowCodecID Add encodes rows with and the built
+// Block is tagged with, so a reader picks the matching row decoder instead
+// of assuming RowCodecV0. See RowCodecV1 for the tradeoff the other option
+// makes.
+func WithRowCodec(id RowCodecID) BuilderOption {
+	return func(b *Builder) {
+		b.rowCodec = id
+	}
+}
+
+// NewBuilder builds a block of key values, in the v0RowCodec format unless
+// WithRowCodec says otherwise, along with the Block.Offsets which point to
+// the beginning of each key/value.
+//
+// Every defaultRestartInterval-th entry is a restart point: it is stored with
+// its full key rather than prefix-compressed against restartBase, and its
+// offset is recorded in Block.Restarts. This lets a reader binary-search the
+// block instead of scanning it linearly.
 //
-// See v0RowCodec for on disk format of the key values.
-func NewBuilder(blockSize uint64) *Builder {
-	return &Builder{
+// See v0RowCodec and v1RowCodec for the on-disk row formats.
+func NewBuilder(blockSize uint64, opts ...BuilderOption) *Builder {
+	b := &Builder{
 		offsets:   make([]uint16, 0),
+		restarts:  make([]uint16, 0),
 		data:      make([]byte, 0),
 		blockSize: blockSize,
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
 func (b *Builder) curBlockSize() int {
 	return common.SizeOfUint16 + // number of key-value pairs in the block
 		(len(b.offsets) * common.SizeOfUint16) + // offsets
+		common.SizeOfUint16 + // number of restart points
+		(len(b.restarts) * common.SizeOfUint16) + // restart points
+		common.SizeOfUint16 + // v1 block magic
 		len(b.data) // Row entries already in the block
 }
 
 // TODO(thrawn01): Should accept a types.EntryRow instead of block.v0Row, I think?
 func (b *Builder) Add(key []byte, row v0Row) bool {
 	assert.True(len(key) > 0, "key must not be empty")
-	row.KeyPrefixLen = computePrefix(b.firstKey, key)
-	row.KeySuffix = key[row.KeyPrefixLen:]
+
+	atRestart := len(b.offsets)%defaultRestartInterval == 0
+	if atRestart {
+		row.KeyPrefixLen = 0
+		row.KeySuffix = key
+	} else {
+		row.KeyPrefixLen = computePrefix(b.restartBase, key)
+		row.KeySuffix = key[row.KeyPrefixLen:]
+	}
+
+	codec := rowCodecFor(b.rowCodec)
 
 	// If adding the key-value pair would exceed the block size limit, don't add it.
 	// (Unless the block is empty, in which case, allow the block to exceed the limit.)
-	if uint64(b.curBlockSize()+row.Size()) > b.blockSize && !b.IsEmpty() {
+	if uint64(b.curBlockSize()+codec.size(row)) > b.blockSize && !b.IsEmpty() {
 		return false
 	}
 
+	if atRestart {
+		b.restarts = append(b.restarts, uint16(len(b.data)))
+		b.restartBase = bytes.Clone(key)
+	}
+
 	b.offsets = append(b.offsets, uint16(len(b.data)))
-	b.data = append(b.data, v0RowCodec.Encode(row)...)
+	b.data = append(b.data, codec.Encode(row)...)
+
+	if b.filterPolicy != nil {
+		b.keys = append(b.keys, bytes.Clone(key))
+	}
 
 	if b.firstKey == nil {
 		b.firstKey = bytes.Clone(key)
@@ -187,13 +722,24 @@ func (b *Builder) Build() (*Block, error) {
 	if b.IsEmpty() {
 		return nil, ErrEmptyBlock
 	}
-	return &Block{
+	blk := &Block{
 		FirstKey: b.firstKey,
 		Offsets:  b.offsets,
+		Restarts: b.restarts,
 		Data:     b.data,
-	}, nil
+		RowCodec: b.rowCodec,
+	}
+	if b.filterPolicy != nil {
+		blk.Filter = b.filterPolicy.Build(b.keys)
+	}
+	return blk, nil
 }
 
+// PrettyPrint renders block's entries one per line, annotated with the
+// on-disk width of each field per the row layout block.RowCodec actually
+// used (see v0RowCodec and v1RowCodec in row.go) -- RowCodecV0's fixed-width
+// KeySuffixLen/ValueLen fields and RowCodecV1's varints and tombstone tag
+// print differently, since they are differently sized on disk.
 func PrettyPrint(block *Block) string {
 	buf := new(bytes.Buffer)
 	it := NewIterator(block)
@@ -203,6 +749,19 @@ func PrettyPrint(block *Block) string {
 			_, _ = fmt.Fprintf(buf, "WARN: there are more offsets than blocks")
 		}
 		_, _ = fmt.Fprintf(buf, "Offset: %d\n", offset)
+		if block.RowCodec == RowCodecV1 {
+			_, _ = fmt.Fprintf(buf, "  varint(%d) - key length\n", len(kv.Key))
+			_, _ = fmt.Fprintf(buf, "  []byte(\"%s\") - %d bytes\n", Truncate(kv.Key, 30), len(kv.Key))
+			if kv.Value.IsTombstone() {
+				_, _ = fmt.Fprintf(buf, "  byte(%d) - tombstone tag\n", v1TagTombstone)
+			} else {
+				v := kv.Value.Value
+				_, _ = fmt.Fprintf(buf, "  byte(%d) - value tag\n", v1TagValue)
+				_, _ = fmt.Fprintf(buf, "  varint(%d) - ValueLen\n", len(v))
+				_, _ = fmt.Fprintf(buf, "  []byte(\"%s\") - %d bytes\n", Truncate(v, 30), len(v))
+			}
+			continue
+		}
 		_, _ = fmt.Fprintf(buf, "  uint16(%d) - 2 bytes\n", len(kv.Key))
 		_, _ = fmt.Fprintf(buf, "  []byte(\"%s\") - %d bytes\n", Truncate(kv.Key, 30), len(kv.Key))
 		if kv.Value.IsTombstone() {
@@ -2,11 +2,13 @@ package block_test
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/sstable/block"
 	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 )
 
@@ -234,6 +236,564 @@ func TestNewBuilderWithOffsets(t *testing.T) {
 	}
 }
 
+func TestBlockRestartPoints(t *testing.T) {
+	bb := block.NewBuilder(4096)
+	for i := 0; i < 40; i++ {
+		key := []byte(fmt.Sprintf("key%02d", i))
+		assert.True(t, bb.AddValue(key, []byte("value")))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+
+	// A restart point every 16 entries across 40 entries lands at indexes 0, 16, 32.
+	assert.Equal(t, 3, len(b.Restarts))
+
+	encoded, err := block.Encode(b, compress.CodecNone)
+	require.NoError(t, err)
+
+	var decoded block.Block
+	require.NoError(t, block.Decode(&decoded, encoded, compress.CodecNone))
+	assert.Equal(t, b.Restarts, decoded.Restarts)
+	assert.Equal(t, b.Offsets, decoded.Offsets)
+	assert.Equal(t, b.Data, decoded.Data)
+}
+
+// TestNewIteratorAtKeySpansManyRestartWindows exercises NewIteratorAtKey's
+// binary search (restartFloor) across a block wide enough to span several
+// restart windows, seeking to keys that land exactly on a restart point,
+// strictly inside a window, and past the last key -- not just checking that
+// Block.Restarts round-trips through Encode/Decode.
+func TestNewIteratorAtKeySpansManyRestartWindows(t *testing.T) {
+	const numKeys = 200 // 12+ restart windows at the default interval of 16
+
+	bb := block.NewBuilder(64 * 1024)
+	for i := 0; i < numKeys; i++ {
+		key := []byte(fmt.Sprintf("key%04d", i))
+		assert.True(t, bb.AddValue(key, []byte(fmt.Sprintf("value%04d", i))))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	require.Greater(t, len(b.Restarts), 5)
+
+	seekTo := func(i int) []byte { return []byte(fmt.Sprintf("key%04d", i)) }
+
+	testCases := []struct {
+		name     string
+		seek     int
+		wantFrom int
+	}{
+		{"on a restart point", 32, 32},
+		{"mid-window", 45, 45},
+		{"first key", 0, 0},
+		{"last key", numKeys - 1, numKeys - 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			iter, err := block.NewIteratorAtKey(b, seekTo(tc.seek))
+			require.NoError(t, err)
+
+			for i := tc.wantFrom; i < numKeys; i++ {
+				entry, ok := iter.NextEntry()
+				require.True(t, ok)
+				assert.Equal(t, string(seekTo(i)), string(entry.Key))
+			}
+			_, ok := iter.NextEntry()
+			assert.False(t, ok)
+		})
+	}
+
+	t.Run("past the last key", func(t *testing.T) {
+		iter, err := block.NewIteratorAtKey(b, []byte("zzzzzzzz"))
+		require.NoError(t, err)
+		_, ok := iter.NextEntry()
+		assert.False(t, ok)
+	})
+
+	t.Run("between two keys", func(t *testing.T) {
+		// "key0031z" sorts between key0031 and key0032, landing inside restart
+		// window 1 (entries 16-31) rather than on its boundary.
+		iter, err := block.NewIteratorAtKey(b, []byte("key0031z"))
+		require.NoError(t, err)
+		entry, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, string(seekTo(32)), string(entry.Key))
+	})
+}
+
+// TestNewIteratorAtKeyRowCodecV1LongRestartKey guards restartFloor/restartKey
+// against assuming v0RowCodec's fixed-width KeySuffixLen field: a RowCodecV1
+// key >= 128 bytes needs two varint bytes to encode its length, so decoding a
+// restart point's key with the wrong codec reads the wrong length and slices
+// the key starting one byte too early.
+func TestNewIteratorAtKeyRowCodecV1LongRestartKey(t *testing.T) {
+	const numKeys = 40 // several restart windows at the default interval of 16
+
+	longKey := func(i int) []byte {
+		return []byte(fmt.Sprintf("key%04d-%s", i, strings.Repeat("x", 200)))
+	}
+
+	bb := block.NewBuilder(64*1024, block.WithRowCodec(block.RowCodecV1))
+	for i := 0; i < numKeys; i++ {
+		assert.True(t, bb.AddValue(longKey(i), []byte(fmt.Sprintf("value%04d", i))))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	require.Greater(t, len(b.Restarts), 1)
+
+	// key0020 lies inside the window of the second restart point (index 16;
+	// 32 is the third); seeking to it must land in the right window, not one
+	// decoded from a key corrupted by reading it with rowCodecV0's layout.
+	iter, err := block.NewIteratorAtKey(b, longKey(20))
+	require.NoError(t, err)
+	for i := 20; i < numKeys; i++ {
+		entry, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, string(longKey(i)), string(entry.Key))
+	}
+	_, ok := iter.NextEntry()
+	assert.False(t, ok)
+}
+
+func TestBlockIteratorPrevEntry(t *testing.T) {
+	kvPairs := []types.KeyValue{
+		{Key: []byte("donkey"), Value: []byte("kong")},
+		{Key: []byte("kratos"), Value: []byte("atreus")},
+		{Key: []byte("super"), Value: []byte("mario")},
+	}
+
+	bb := block.NewBuilder(1024)
+	for _, kv := range kvPairs {
+		assert.True(t, bb.AddValue(kv.Key, kv.Value))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+
+	t.Run("from the end", func(t *testing.T) {
+		iter := block.NewIterator(b)
+		for _, ok := iter.NextEntry(); ok; _, ok = iter.NextEntry() {
+		}
+
+		for i := len(kvPairs) - 1; i >= 0; i-- {
+			entry, ok := iter.PrevEntry()
+			require.True(t, ok)
+			assert.Equal(t, string(kvPairs[i].Key), string(entry.Key))
+			assert.Equal(t, string(kvPairs[i].Value), string(entry.Value.Value))
+		}
+
+		_, ok := iter.PrevEntry()
+		assert.False(t, ok)
+	})
+
+	t.Run("interleaved with NextEntry", func(t *testing.T) {
+		iter := block.NewIterator(b)
+		first, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, string(kvPairs[0].Key), string(first.Key))
+
+		second, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, string(kvPairs[1].Key), string(second.Key))
+
+		back, ok := iter.PrevEntry()
+		require.True(t, ok)
+		assert.Equal(t, string(kvPairs[0].Key), string(back.Key))
+
+		_, ok = iter.PrevEntry()
+		assert.False(t, ok)
+
+		forward, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, string(kvPairs[0].Key), string(forward.Key))
+	})
+}
+
+// TestBlockIteratorReverseAcrossManyRestartWindows builds a block spanning
+// several restart windows, walks it forward to the end with NextEntry, then
+// walks it all the way back with PrevEntry -- exercising decodeAt's
+// restart-window replay across window boundaries (not just a 2-3 key block
+// whose rows all fall in the first restart window).
+func TestBlockIteratorReverseAcrossManyRestartWindows(t *testing.T) {
+	const numKeys = 200
+
+	bb := block.NewBuilder(64 * 1024)
+	for i := 0; i < numKeys; i++ {
+		assert.True(t, bb.AddValue([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%04d", i))))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	require.Greater(t, len(b.Restarts), 5)
+
+	iter := block.NewIterator(b)
+	for i := 0; i < numKeys; i++ {
+		entry, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("key%04d", i), string(entry.Key))
+	}
+	_, ok := iter.NextEntry()
+	require.False(t, ok)
+
+	for i := numKeys - 1; i >= 0; i-- {
+		entry, ok := iter.PrevEntry()
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("key%04d", i), string(entry.Key))
+		assert.Equal(t, fmt.Sprintf("value%04d", i), string(entry.Value.Value))
+	}
+	_, ok = iter.PrevEntry()
+	assert.False(t, ok)
+}
+
+func TestBlockIteratorSeekGE(t *testing.T) {
+	const numKeys = 200
+
+	bb := block.NewBuilder(64 * 1024)
+	for i := 0; i < numKeys; i++ {
+		assert.True(t, bb.AddValue([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%04d", i))))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+
+	iter := block.NewIterator(b)
+	iter.SeekGE([]byte("key0100z")) // between key0100 and key0101
+
+	entry, ok := iter.NextEntry()
+	require.True(t, ok)
+	assert.Equal(t, "key0101", string(entry.Key))
+
+	// Re-seeking the same Iterator (rather than building a new one) must
+	// still land correctly.
+	iter.SeekGE([]byte("key0003"))
+	entry, ok = iter.NextEntry()
+	require.True(t, ok)
+	assert.Equal(t, "key0003", string(entry.Key))
+}
+
+func TestBlockIteratorSeekLE(t *testing.T) {
+	const numKeys = 200
+
+	bb := block.NewBuilder(64 * 1024)
+	for i := 0; i < numKeys; i++ {
+		assert.True(t, bb.AddValue([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%04d", i))))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+
+	t.Run("exact match", func(t *testing.T) {
+		iter := block.NewIterator(b)
+		iter.SeekLE([]byte("key0100"))
+		entry, ok := iter.PrevEntry()
+		require.True(t, ok)
+		assert.Equal(t, "key0100", string(entry.Key))
+	})
+
+	t.Run("between two keys", func(t *testing.T) {
+		iter := block.NewIterator(b)
+		iter.SeekLE([]byte("key0100z"))
+		entry, ok := iter.PrevEntry()
+		require.True(t, ok)
+		assert.Equal(t, "key0100", string(entry.Key))
+	})
+
+	t.Run("before the first key", func(t *testing.T) {
+		iter := block.NewIterator(b)
+		iter.SeekLE([]byte("aaa"))
+		_, ok := iter.PrevEntry()
+		assert.False(t, ok)
+	})
+
+	t.Run("after the last key", func(t *testing.T) {
+		iter := block.NewIterator(b)
+		iter.SeekLE([]byte("zzz"))
+		entry, ok := iter.PrevEntry()
+		require.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("key%04d", numKeys-1), string(entry.Key))
+	})
+}
+
+func TestBlockFilter(t *testing.T) {
+	kvPairs := []types.KeyValue{
+		{Key: []byte("donkey"), Value: []byte("kong")},
+		{Key: []byte("kratos"), Value: []byte("atreus")},
+		{Key: []byte("super"), Value: []byte("mario")},
+	}
+
+	bb := block.NewBuilder(1024, block.WithFilterPolicy(block.NewBloomFilterPolicy(10)))
+	for _, kv := range kvPairs {
+		assert.True(t, bb.AddValue(kv.Key, kv.Value))
+	}
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	assert.NotEmpty(t, b.Filter)
+
+	for _, kv := range kvPairs {
+		assert.True(t, b.MayContain(kv.Key))
+	}
+	assert.False(t, b.MayContain([]byte("definitely-not-in-the-block")))
+
+	encoded, err := block.Encode(b, compress.CodecNone)
+	require.NoError(t, err)
+
+	var decoded block.Block
+	require.NoError(t, block.Decode(&decoded, encoded, compress.CodecNone))
+	assert.Equal(t, b.Filter, decoded.Filter)
+	assert.Equal(t, b.Data, decoded.Data)
+	assert.Equal(t, b.Offsets, decoded.Offsets)
+	assert.Equal(t, b.Restarts, decoded.Restarts)
+	assert.True(t, decoded.MayContain([]byte("kratos")))
+}
+
+func TestBlockWithoutFilter(t *testing.T) {
+	bb := block.NewBuilder(1024)
+	assert.True(t, bb.AddValue([]byte("key1"), []byte("value1")))
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	assert.Empty(t, b.Filter)
+
+	// Blocks built without WithFilterPolicy always report a possible match,
+	// deferring to the iterator.
+	assert.True(t, b.MayContain([]byte("anything")))
+}
+
+func TestBlockDecodeAuto(t *testing.T) {
+	bb := block.NewBuilder(4096)
+	assert.True(t, bb.AddValue([]byte("key1"), []byte("value1")))
+	assert.True(t, bb.AddValue([]byte("key2"), []byte("value2")))
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+
+	encoded, err := block.Encode(b, compress.CodecLz4)
+	require.NoError(t, err)
+
+	var decoded block.Block
+	require.NoError(t, block.DecodeAuto(&decoded, encoded))
+	assert.Equal(t, b.FirstKey, decoded.FirstKey)
+	assert.Equal(t, b.Data, decoded.Data)
+	assert.Equal(t, b.Offsets, decoded.Offsets)
+}
+
+func TestBlockDecodeFrom(t *testing.T) {
+	codecs := []struct {
+		name  string
+		codec compress.Codec
+	}{
+		{"CodecNone", compress.CodecNone},
+		{"CodecLz4", compress.CodecLz4},
+	}
+
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			bb := block.NewBuilder(4096)
+			assert.True(t, bb.AddValue([]byte("key1"), []byte("value1")))
+			assert.True(t, bb.AddValue([]byte("key2"), []byte("value2")))
+
+			b, err := bb.Build()
+			require.NoError(t, err)
+
+			encoded, err := block.Encode(b, c.codec)
+			require.NoError(t, err)
+
+			var decoded block.Block
+			require.NoError(t, block.DecodeFrom(bytes.NewReader(encoded), 0, int64(len(encoded)), c.codec, &decoded))
+			assert.Equal(t, b.FirstKey, decoded.FirstKey)
+			assert.Equal(t, b.Data, decoded.Data)
+			assert.Equal(t, b.Offsets, decoded.Offsets)
+
+			decoded.Release()
+			assert.Equal(t, block.Block{}, decoded)
+		})
+	}
+}
+
+func TestBlockRowCodecDefaultsToV0(t *testing.T) {
+	bb := block.NewBuilder(4096)
+	assert.True(t, bb.AddValue([]byte("key1"), []byte("value1")))
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	assert.Equal(t, block.RowCodecV0, b.RowCodec)
+
+	encoded, err := block.Encode(b, compress.CodecNone)
+	require.NoError(t, err)
+
+	var decoded block.Block
+	require.NoError(t, block.DecodeAuto(&decoded, encoded))
+	assert.Equal(t, block.RowCodecV0, decoded.RowCodec)
+}
+
+func TestBlockWithRowCodecV1(t *testing.T) {
+	kvPairs := []types.KeyValue{
+		{Key: []byte("donkey"), Value: []byte("kong")},
+		{Key: []byte("kratos"), Value: []byte("atreus")},
+		{Key: []byte("super"), Value: []byte("mario")},
+	}
+
+	bb := block.NewBuilder(1024, block.WithRowCodec(block.RowCodecV1))
+	for _, kv := range kvPairs {
+		assert.True(t, bb.AddValue(kv.Key, kv.Value))
+	}
+	assert.True(t, bb.AddValue([]byte("tombstoned"), nil))
+
+	b, err := bb.Build()
+	require.NoError(t, err)
+	assert.Equal(t, block.RowCodecV1, b.RowCodec)
+	assert.Equal(t, []byte("donkey"), b.FirstKey)
+
+	encoded, err := block.Encode(b, compress.CodecNone)
+	require.NoError(t, err)
+
+	var decoded block.Block
+	require.NoError(t, block.DecodeAuto(&decoded, encoded))
+	assert.Equal(t, block.RowCodecV1, decoded.RowCodec)
+	assert.Equal(t, b.FirstKey, decoded.FirstKey)
+
+	iter := block.NewIterator(&decoded)
+	for _, kv := range kvPairs {
+		entry, ok := iter.NextEntry()
+		require.True(t, ok)
+		assert.Equal(t, kv.Key, entry.Key)
+		assert.Equal(t, kv.Value, entry.Value.Value)
+		assert.False(t, entry.Value.IsTombstone())
+	}
+	entry, ok := iter.NextEntry()
+	require.True(t, ok)
+	assert.Equal(t, []byte("tombstoned"), entry.Key)
+	assert.True(t, entry.Value.IsTombstone())
+
+	_, ok = iter.NextEntry()
+	assert.False(t, ok)
+}
+
+func TestRowCodecV1SmallerThanV0(t *testing.T) {
+	build := func(codec block.RowCodecID) int {
+		bb := block.NewBuilder(64*1024, block.WithRowCodec(codec))
+		for i := 0; i < 100; i++ {
+			assert.True(t, bb.AddValue([]byte(fmt.Sprintf("key%03d", i)), []byte("v")))
+		}
+		b, err := bb.Build()
+		require.NoError(t, err)
+		return len(b.Data)
+	}
+
+	v0Size := build(block.RowCodecV0)
+	v1Size := build(block.RowCodecV1)
+	assert.Less(t, v1Size, v0Size, "v1's varint lengths and 1-byte tag should beat v0's fixed 2/2/4-byte fields on short keys/values")
+}
+
+// BenchmarkBlockBuild compares the two row codecs' build throughput, and
+// (via the reported bytes/op) their encoded size, on a short-key/value
+// workload representative of what WithRowCodec(RowCodecV1) targets.
+func BenchmarkBlockBuild(b *testing.B) {
+	codecs := []struct {
+		name  string
+		codec block.RowCodecID
+	}{
+		{"V0", block.RowCodecV0},
+		{"V1", block.RowCodecV1},
+	}
+
+	for _, c := range codecs {
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bb := block.NewBuilder(1<<20, block.WithRowCodec(c.codec))
+				for j := 0; j < 1000; j++ {
+					bb.AddValue([]byte(fmt.Sprintf("key%05d", j)), []byte("value"))
+				}
+				blk, err := bb.Build()
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(blk.Data)))
+				b.ReportMetric(float64(len(blk.Data)), "bytes/op")
+			}
+		})
+	}
+}
+
+// FuzzBlockDecode round-trips two-row blocks through Encode/DecodeAuto across
+// every compress.Codec this package currently supports, checking that the
+// decoded Block matches the one that went in. CodecSnappy and CodecZstd
+// aren't covered here: the compress package doesn't implement them in this
+// tree yet, so there's nothing to add them to (see chunk0-3).
+func FuzzBlockDecode(f *testing.F) {
+	f.Add([]byte("key1"), []byte("value1"), []byte("key2"), []byte("value2"))
+
+	f.Fuzz(func(t *testing.T, k1, v1, k2, v2 []byte) {
+		if len(k1) == 0 || len(k2) == 0 || len(v1) == 0 || len(v2) == 0 || bytes.Equal(k1, k2) {
+			t.Skip()
+		}
+		if bytes.Compare(k1, k2) > 0 {
+			k1, k2 = k2, k1
+		}
+
+		for _, codec := range []compress.Codec{compress.CodecNone, compress.CodecLz4} {
+			bb := block.NewBuilder(64 * 1024)
+			require.True(t, bb.AddValue(k1, v1))
+			require.True(t, bb.AddValue(k2, v2))
+			want, err := bb.Build()
+			require.NoError(t, err)
+
+			encoded, err := block.Encode(want, codec)
+			require.NoError(t, err)
+
+			var got block.Block
+			require.NoError(t, block.DecodeAuto(&got, encoded))
+			assert.Equal(t, want.FirstKey, got.FirstKey)
+			assert.Equal(t, want.Data, got.Data)
+			assert.Equal(t, want.Offsets, got.Offsets)
+			assert.Equal(t, want.Restarts, got.Restarts)
+		}
+	})
+}
+
+// BenchmarkBlockEncodeDecode compares DecodeAuto throughput across the
+// compress.Codec values this package currently supports; each codec's block
+// is encoded once, outside the timed loop. CodecSnappy and
+// CodecZstd are left out for the same reason as FuzzBlockDecode above.
+func BenchmarkBlockEncodeDecode(b *testing.B) {
+	codecs := []struct {
+		name  string
+		codec compress.Codec
+	}{
+		{"CodecNone", compress.CodecNone},
+		{"CodecLz4", compress.CodecLz4},
+	}
+
+	for _, c := range codecs {
+		bb := block.NewBuilder(64 * 1024)
+		for i := 0; i < 1000; i++ {
+			bb.AddValue([]byte(fmt.Sprintf("key%05d", i)), []byte("value"))
+		}
+		blk, err := bb.Build()
+		if err != nil {
+			b.Fatal(err)
+		}
+		encoded, err := block.Encode(blk, c.codec)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(c.name, func(b *testing.B) {
+			b.SetBytes(int64(len(blk.Data)))
+			for i := 0; i < b.N; i++ {
+				var decoded block.Block
+				if err := block.DecodeAuto(&decoded, encoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	testCases := []struct {
 		input    []byte
@@ -268,6 +828,28 @@ func TestPrettyPrint(t *testing.T) {
 	assert.Contains(t, out, "data-intensive")
 	assert.Contains(t, out, "applications")
 	assert.Contains(t, out, "deleted")
+	assert.Contains(t, out, "uint16")
+	assert.Contains(t, out, "uint32")
+}
+
+// TestPrettyPrintRowCodecV1 guards PrettyPrint against describing a
+// RowCodecV1 block's rows as if they were RowCodecV0's fixed-width fields --
+// RowCodecV1 uses varints and a 1-byte tombstone tag instead.
+func TestPrettyPrintRowCodecV1(t *testing.T) {
+	bb := block.NewBuilder(4096, block.WithRowCodec(block.RowCodecV1))
+	assert.True(t, bb.AddValue([]byte("database"), []byte("internals")))
+	assert.True(t, bb.AddValue([]byte("deleted"), []byte("")))
+
+	b, err := bb.Build()
+	assert.NoError(t, err)
+	out := block.PrettyPrint(b)
+
+	assert.Contains(t, out, "database")
+	assert.Contains(t, out, "internals")
+	assert.Contains(t, out, "deleted")
+	assert.Contains(t, out, "varint")
+	assert.NotContains(t, out, "uint16")
+	assert.NotContains(t, out, "uint32")
 }
 
 func TestBlockFirstKey(t *testing.T) {
@@ -0,0 +1,142 @@
+package block
+
+import (
+	"bytes"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+)
+
+// Iterator sequentially decodes the prefix-compressed rows in a Block,
+// reconstructing each row's full key from the full key of the row before it.
+// The zero value is
+// not usable; construct one with NewIterator or NewIteratorAtKey.
+type Iterator struct {
+	block *Block
+	// curIdx is the index into block.Offsets of the last row NextEntry or
+	// PrevEntry returned: -1 before forward iteration starts, len(Offsets)
+	// once it's been exhausted.
+	curIdx int
+	// curKey is the full key of block.Offsets[curIdx], valid whenever curIdx
+	// is in range. NextEntry uses it as the prefix-decompression base for
+	// curIdx+1, so it must always match whatever row curIdx actually is.
+	curKey []byte
+}
+
+// NewIterator returns an Iterator positioned before the first row of b.
+func NewIterator(b *Block) *Iterator {
+	return &Iterator{block: b, curIdx: -1}
+}
+
+// NewIteratorAtKey returns an Iterator positioned so NextEntry/Next returns
+// the first row with key >= key. It binary-searches Block.Restarts to find
+// the restart point at or before key (see restartFloor) and decodes forward
+// from there, rather than scanning the block from the start.
+func NewIteratorAtKey(b *Block, key []byte) (*Iterator, error) {
+	it := NewIterator(b)
+	it.seekGE(key)
+	return it, nil
+}
+
+// NextEntry advances the iterator and returns the next row, or
+// (types.RowEntry{}, false) once every row has been returned.
+func (it *Iterator) NextEntry() (types.RowEntry, bool) {
+	target := it.curIdx + 1
+	if target >= len(it.block.Offsets) {
+		it.curIdx = len(it.block.Offsets)
+		return types.RowEntry{}, false
+	}
+
+	row, _ := rowCodecFor(it.block.RowCodec).Decode(it.block.Data[it.block.Offsets[target]:])
+	key := row.KeySuffix
+	if row.KeyPrefixLen > 0 {
+		key = append(append([]byte(nil), it.curKey[:row.KeyPrefixLen]...), row.KeySuffix...)
+	}
+
+	it.curIdx = target
+	it.curKey = key
+	return types.RowEntry{Key: key, Value: row.Value}, true
+}
+
+// Next is NextEntry without tombstone information, for callers that don't
+// need to distinguish a deleted key from one that was never present.
+func (it *Iterator) Next() (types.KeyValue, bool) {
+	entry, ok := it.NextEntry()
+	if !ok {
+		return types.KeyValue{}, false
+	}
+	return types.KeyValue{Key: entry.Key, Value: entry.Value.Value}, true
+}
+
+// indexGE returns the index of the first row in the block with key >=
+// target, or len(Offsets) if every key is smaller. It binary-searches
+// Block.Restarts via restartFloor to find the restart window that might
+// contain target, then decodes forward only within that window -- O(log n +
+// k) instead of the O(n) scan this replaces.
+func (it *Iterator) indexGE(target []byte) int {
+	data, offsets := it.block.Data, it.block.Offsets
+
+	windowStart := 0
+	if r := restartFloor(data, it.block.Restarts, it.block.RowCodec, target); r >= 0 {
+		windowStart = r * defaultRestartInterval
+	}
+
+	// windowStart is always a restart point, whose row has KeyPrefixLen == 0
+	// by construction (see Builder.Add), so base is never read on the first
+	// iteration below -- it's assigned the restart row's full key before the
+	// second iteration could need it.
+	var base []byte
+	for i := windowStart; i < len(offsets); i++ {
+		row, _ := rowCodecFor(it.block.RowCodec).Decode(data[offsets[i]:])
+		full := row.KeySuffix
+		if row.KeyPrefixLen > 0 {
+			full = append(append([]byte(nil), base[:row.KeyPrefixLen]...), row.KeySuffix...)
+		}
+		if bytes.Compare(full, target) >= 0 {
+			return i
+		}
+		base = full
+	}
+	return len(offsets)
+}
+
+// decodeAt fully decodes the row at idx by replaying forward from the
+// nearest restart point at or before it (see restartWindowEnd), the same way
+// PrevEntry does -- prefix-compressed rows can't be decoded starting from
+// the middle of a restart window, only from its start.
+func (it *Iterator) decodeAt(idx int) ([]byte, types.Value) {
+	data, offsets, restarts := it.block.Data, it.block.Offsets, it.block.Restarts
+	codec := rowCodecFor(it.block.RowCodec)
+
+	restartIdx, windowStart := 0, 0
+	if len(restarts) > 0 {
+		restartIdx = idx / defaultRestartInterval
+		windowStart = restartIdx * defaultRestartInterval
+	}
+	windowEnd := restartWindowEnd(data, restarts, restartIdx)
+
+	var base, key []byte
+	var value types.Value
+	for i := windowStart; i <= idx && offsets[i] < windowEnd; i++ {
+		row, _ := codec.Decode(data[offsets[i]:])
+		key = row.KeySuffix
+		if row.KeyPrefixLen > 0 {
+			key = append(append([]byte(nil), base[:row.KeyPrefixLen]...), row.KeySuffix...)
+		}
+		base = key
+		value = row.Value
+	}
+	return key, value
+}
+
+// seekGE repositions it so NextEntry/Next returns the first row with key >=
+// target. NewIteratorAtKey uses this to build an already-positioned
+// Iterator; see SeekGE in reverse.go to re-seek an existing one.
+func (it *Iterator) seekGE(target []byte) {
+	idx := it.indexGE(target)
+	it.curIdx = idx - 1
+	if it.curIdx >= 0 {
+		it.curKey, _ = it.decodeAt(it.curIdx)
+	} else {
+		it.curKey = nil
+	}
+}
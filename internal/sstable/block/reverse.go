@@ -0,0 +1,58 @@
+package block
+
+import (
+	"bytes"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+)
+
+// SeekGE repositions it so NextEntry/Next returns the first row with key >=
+// target. Unlike NewIteratorAtKey, an existing Iterator can be re-seeked
+// without allocating a new one.
+func (it *Iterator) SeekGE(target []byte) {
+	it.seekGE(target)
+}
+
+// SeekLE repositions it so PrevEntry returns the last row with key <=
+// target, or false if every row's key is greater. It's the reverse-scan
+// counterpart to SeekGE, for callers walking a block backwards from an upper
+// bound.
+func (it *Iterator) SeekLE(target []byte) {
+	idx := it.indexGE(target)
+	floor := idx - 1
+	if idx < len(it.block.Offsets) {
+		if key, _ := it.decodeAt(idx); bytes.Equal(key, target) {
+			floor = idx
+		}
+	}
+
+	it.curIdx = floor + 1
+	if it.curIdx >= 0 && it.curIdx < len(it.block.Offsets) {
+		it.curKey, _ = it.decodeAt(it.curIdx)
+	} else {
+		it.curKey = nil
+	}
+}
+
+// PrevEntry moves the iterator one row backward and returns it, or
+// (types.RowEntry{}, false) if already at the first row.
+//
+// Rows only encode KeyPrefixLen against the previous full key, so a row
+// can't be decoded in isolation starting from the middle of a restart
+// window -- PrevEntry instead calls decodeAt, which walks back to the
+// nearest restart point at or before the target row (restartWindowEnd marks
+// where that window ends) and decodes forward from there, the same
+// mechanism SeekLE and indexGE use for forward seeks.
+func (it *Iterator) PrevEntry() (types.RowEntry, bool) {
+	target := it.curIdx - 1
+	if target < 0 {
+		it.curIdx = -1
+		it.curKey = nil
+		return types.RowEntry{}, false
+	}
+
+	key, value := it.decodeAt(target)
+	it.curIdx = target
+	it.curKey = key
+	return types.RowEntry{Key: key, Value: value}, true
+}
@@ -0,0 +1,187 @@
+package block
+
+import (
+	"encoding/binary"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// v0Row is the in-memory representation of a single block entry.
+// KeyPrefixLen and KeySuffix hold the row's key prefix-compressed against
+// whichever restart point precedes it in Block.Data (see Builder.Add);
+// KeyPrefixLen is 0 at every restart point, where KeySuffix is the full key.
+type v0Row struct {
+	KeyPrefixLen uint16
+	KeySuffix    []byte
+	Value        types.Value
+}
+
+// rowCodec encodes and decodes a v0Row using one of the block package's row
+// formats. RowCodecID selects which implementation a Builder uses and which
+// one Decode/DecodeAuto/DecodeFrom select when reading a block back.
+type rowCodec interface {
+	Encode(row v0Row) []byte
+	// Decode reads a single row starting at data[0], returning the row and
+	// the number of bytes it consumed so the caller can advance to the next
+	// row's offset.
+	Decode(data []byte) (v0Row, int)
+	size(row v0Row) int
+}
+
+// rowCodecFor returns the rowCodec matching id, defaulting to v0RowCodec for
+// any id it doesn't recognize (in practice, RowCodecV0 itself).
+func rowCodecFor(id RowCodecID) rowCodec {
+	if id == RowCodecV1 {
+		return v1RowCodec
+	}
+	return v0RowCodec
+}
+
+// v0RowCodec is the block package's original row format: fixed-width
+// KeySuffixLen/KeyPrefixLen/ValueLen fields, with math.MaxUint32 (Tombstone)
+// as the deletion sentinel in place of ValueLen.
+var v0RowCodec = rowCodecV0{}
+
+type rowCodecV0 struct{}
+
+// Encode serializes row as:
+//
+//	KeySuffixLen (2 bytes) | KeySuffix | KeyPrefixLen (2 bytes) |
+//	ValueLen (4 bytes, or Tombstone) | Value (absent for a tombstone)
+func (rowCodecV0) Encode(row v0Row) []byte {
+	out := make([]byte, 0, rowCodecV0{}.size(row))
+	out = binary.BigEndian.AppendUint16(out, uint16(len(row.KeySuffix)))
+	out = append(out, row.KeySuffix...)
+	out = binary.BigEndian.AppendUint16(out, row.KeyPrefixLen)
+	if row.Value.IsTombstone() {
+		out = binary.BigEndian.AppendUint32(out, Tombstone)
+		return out
+	}
+	out = binary.BigEndian.AppendUint32(out, uint32(len(row.Value.Value)))
+	out = append(out, row.Value.Value...)
+	return out
+}
+
+func (rowCodecV0) Decode(data []byte) (v0Row, int) {
+	suffixLen := binary.BigEndian.Uint16(data)
+	pos := common.SizeOfUint16
+	suffix := data[pos : pos+int(suffixLen)]
+	pos += int(suffixLen)
+
+	prefixLen := binary.BigEndian.Uint16(data[pos:])
+	pos += common.SizeOfUint16
+
+	valueLen := binary.BigEndian.Uint32(data[pos:])
+	pos += common.SizeOfUint32
+
+	if valueLen == Tombstone {
+		return v0Row{KeyPrefixLen: prefixLen, KeySuffix: suffix, Value: types.Value{Kind: types.KindTombStone}}, pos
+	}
+	value := data[pos : pos+int(valueLen)]
+	pos += int(valueLen)
+	return v0Row{KeyPrefixLen: prefixLen, KeySuffix: suffix, Value: types.Value{Value: value}}, pos
+}
+
+func (rowCodecV0) size(row v0Row) int {
+	size := common.SizeOfUint16 + len(row.KeySuffix) + common.SizeOfUint16 + common.SizeOfUint32
+	if !row.Value.IsTombstone() {
+		size += len(row.Value.Value)
+	}
+	return size
+}
+
+// v1RowCodec is RowCodecV1: the same KeyPrefixLen/KeySuffix/Value fields as
+// v0Row, but varint-encoded lengths and a single tombstone-tag byte instead
+// of v0RowCodec's fixed 2/2/4-byte fields and the Tombstone sentinel. For
+// workloads with short keys and values this trims several bytes per row.
+var v1RowCodec = rowCodecV1{}
+
+type rowCodecV1 struct{}
+
+const (
+	v1TagValue     byte = 0
+	v1TagTombstone byte = 1
+)
+
+// Encode serializes row as:
+//
+//	KeyPrefixLen (varint) | KeySuffixLen (varint) | KeySuffix |
+//	tag (1 byte) | ValueLen (varint, absent for a tombstone) | Value
+func (rowCodecV1) Encode(row v0Row) []byte {
+	out := make([]byte, 0, rowCodecV1{}.size(row))
+	var scratch [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(scratch[:], uint64(row.KeyPrefixLen))
+	out = append(out, scratch[:n]...)
+
+	n = binary.PutUvarint(scratch[:], uint64(len(row.KeySuffix)))
+	out = append(out, scratch[:n]...)
+	out = append(out, row.KeySuffix...)
+
+	if row.Value.IsTombstone() {
+		return append(out, v1TagTombstone)
+	}
+
+	out = append(out, v1TagValue)
+	n = binary.PutUvarint(scratch[:], uint64(len(row.Value.Value)))
+	out = append(out, scratch[:n]...)
+	return append(out, row.Value.Value...)
+}
+
+func (rowCodecV1) Decode(data []byte) (v0Row, int) {
+	prefixLen, n := binary.Uvarint(data)
+	pos := n
+
+	suffixLen, n := binary.Uvarint(data[pos:])
+	pos += n
+
+	suffix := data[pos : pos+int(suffixLen)]
+	pos += int(suffixLen)
+
+	tag := data[pos]
+	pos++
+
+	if tag == v1TagTombstone {
+		return v0Row{KeyPrefixLen: uint16(prefixLen), KeySuffix: suffix, Value: types.Value{Kind: types.KindTombStone}}, pos
+	}
+
+	valueLen, n := binary.Uvarint(data[pos:])
+	pos += n
+	value := data[pos : pos+int(valueLen)]
+	pos += int(valueLen)
+	return v0Row{KeyPrefixLen: uint16(prefixLen), KeySuffix: suffix, Value: types.Value{Value: value}}, pos
+}
+
+func (rowCodecV1) size(row v0Row) int {
+	size := uvarintLen(uint64(row.KeyPrefixLen)) + uvarintLen(uint64(len(row.KeySuffix))) + len(row.KeySuffix) + 1
+	if !row.Value.IsTombstone() {
+		size += uvarintLen(uint64(len(row.Value.Value))) + len(row.Value.Value)
+	}
+	return size
+}
+
+// uvarintLen returns the number of bytes binary.PutUvarint would write for x,
+// without actually encoding it.
+func uvarintLen(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
+
+// computePrefix returns the length of the common prefix shared by base and
+// key, capped at len(base) since KeyPrefixLen can never exceed it.
+func computePrefix(base, key []byte) uint16 {
+	max := len(base)
+	if len(key) < max {
+		max = len(key)
+	}
+	n := 0
+	for n < max && base[n] == key[n] {
+		n++
+	}
+	return uint16(n)
+}